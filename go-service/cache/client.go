@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/luciq/chat-go-service/logging"
+	"github.com/redis/go-redis/v9"
+)
+
+// Client wraps a Redis connection and implements sequential-number
+// allocation for chats/messages. It is constructed explicitly, rather than
+// relying on a package-level singleton, so handlers can be unit tested
+// against an injected miniredis instance.
+type Client struct {
+	rdb *redis.Client
+	ctx context.Context
+
+	durable *DurableCounters
+
+	// highWaterMu guards highWater, an in-memory record of the largest
+	// counter value this process has seen Redis hand out or sync to, per
+	// counter key. It's what lets a durable-counter fallback pick up where
+	// Redis left off instead of restarting from 0 if Redis dies mid-run.
+	highWaterMu sync.Mutex
+	highWater   map[string]int64
+}
+
+// NewClient wraps an existing *redis.Client, e.g. one pointed at a
+// miniredis instance in tests.
+func NewClient(rdb *redis.Client) *Client {
+	return &Client{rdb: rdb, ctx: context.Background(), highWater: make(map[string]int64)}
+}
+
+// NewClientFromEnv builds a Client from REDIS_URL (defaulting to
+// localhost:6379) and probes connectivity with a PING. A failed PING is
+// logged, not returned as an error: go-redis reconnects lazily on its own,
+// and callers with durable counters/queue buffering enabled need the
+// service to come up even while Redis is still down.
+func NewClientFromEnv() (*Client, error) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		redisURL = "localhost:6379"
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     redisURL,
+		Password: "", // no password
+		DB:       0,  // default DB
+	})
+
+	c := NewClient(rdb)
+
+	if _, err := c.rdb.Ping(c.ctx).Result(); err != nil {
+		logging.Logger.Warn("Redis unreachable at startup, continuing in degraded mode", "error", err)
+	} else {
+		logging.Logger.Info("Redis connected successfully")
+	}
+
+	return c, nil
+}
+
+// Raw exposes the underlying *redis.Client for callers (e.g. the Sidekiq
+// queue backend) that need direct access to commands Client doesn't wrap.
+func (c *Client) Raw() *redis.Client {
+	return c.rdb
+}
+
+// rememberHighWater records that Redis has handed out (or been synced to)
+// value for key, if it's larger than what's already recorded.
+func (c *Client) rememberHighWater(key string, value int64) {
+	c.highWaterMu.Lock()
+	defer c.highWaterMu.Unlock()
+	if value > c.highWater[key] {
+		c.highWater[key] = value
+	}
+}
+
+// peekHighWater returns the largest value this process has seen Redis
+// hand out or sync to for key, or 0 if none has been observed yet.
+func (c *Client) peekHighWater(key string) int64 {
+	c.highWaterMu.Lock()
+	defer c.highWaterMu.Unlock()
+	return c.highWater[key]
+}
+
+// EnableDurableCounters opens a disk-backed fallback so chat/message
+// number allocation can continue while Redis is unreachable.
+func (c *Client) EnableDurableCounters(dir string) error {
+	d, err := NewDurableCounters(dir)
+	if err != nil {
+		return err
+	}
+	c.durable = d
+	return nil
+}
+
+// Close closes the underlying Redis connection and, if enabled, the
+// durable counter store.
+func (c *Client) Close() error {
+	if c.durable != nil {
+		if err := c.durable.Close(); err != nil {
+			return err
+		}
+	}
+	return c.rdb.Close()
+}
@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/luciq/chat-go-service/logging"
+	"github.com/luciq/chat-go-service/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+// highWaterTTL bounds how long a batch's high-water mark key survives; it
+// only needs to outlive the worst-case Sidekiq processing delay.
+const highWaterTTL = 10 * time.Minute
+
+// allocateBatchScript atomically reserves a contiguous range of n numbers
+// from a counter and records the new high-water mark, so a crashed
+// consumer can be detected by comparing it against MySQL's persisted max.
+var allocateBatchScript = redis.NewScript(`
+local newVal = redis.call('INCRBY', KEYS[1], ARGV[1])
+local startVal = newVal - tonumber(ARGV[1]) + 1
+redis.call('SET', KEYS[2], newVal, 'PX', ARGV[2])
+return {startVal, newVal}
+`)
+
+// syncCounterScript bumps a counter up to at least targetVal, never
+// moving it backwards, so restarting after a crash can't replay numbers
+// MySQL already has.
+var syncCounterScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local target = tonumber(ARGV[1])
+if target > current then
+	redis.call('SET', KEYS[1], target)
+	return target
+end
+return current
+`)
+
+func chatCounterKey(chatApplicationID int64) string {
+	return fmt.Sprintf("chat_app:%d:chat_counter", chatApplicationID)
+}
+
+func chatCounterHighWaterKey(chatApplicationID int64) string {
+	return fmt.Sprintf("chat_app:%d:chat_counter:high_water", chatApplicationID)
+}
+
+func messageCounterKey(chatID int64) string {
+	return fmt.Sprintf("chat:%d:message_counter", chatID)
+}
+
+func messageCounterHighWaterKey(chatID int64) string {
+	return fmt.Sprintf("chat:%d:message_counter:high_water", chatID)
+}
+
+// NextChatNumber generates the next sequential chat number for a chat
+// application. Mimics Rails SequentialNumberService.next_chat_number. If
+// Redis is unreachable and durable counters are enabled, it falls back to
+// the on-disk counter instead of failing the request.
+func (c *Client) NextChatNumber(chatApplicationID int64) (int64, error) {
+	key := chatCounterKey(chatApplicationID)
+
+	var result int64
+	err := metrics.ObserveRedis("incr", func() error {
+		var incrErr error
+		result, incrErr = c.rdb.Incr(c.ctx, key).Result()
+		return incrErr
+	})
+	if err != nil {
+		if c.durable != nil {
+			logging.Logger.Warn("redis unavailable, falling back to durable counter", "key", key, "error", err)
+			if seedErr := c.durable.Seed(key, c.peekHighWater(key)); seedErr != nil {
+				return 0, fmt.Errorf("failed to seed durable counter: %v", seedErr)
+			}
+			return c.durable.Next(key)
+		}
+		return 0, fmt.Errorf("failed to increment chat counter: %v", err)
+	}
+
+	c.rememberHighWater(key, result)
+	return result, nil
+}
+
+// NextMessageNumber generates the next sequential message number for a
+// chat. Mimics Rails SequentialNumberService.next_message_number. Falls
+// back to the durable counter, if enabled, when Redis is unreachable.
+func (c *Client) NextMessageNumber(chatID int64) (int64, error) {
+	key := messageCounterKey(chatID)
+
+	var result int64
+	err := metrics.ObserveRedis("incr", func() error {
+		var incrErr error
+		result, incrErr = c.rdb.Incr(c.ctx, key).Result()
+		return incrErr
+	})
+	if err != nil {
+		if c.durable != nil {
+			logging.Logger.Warn("redis unavailable, falling back to durable counter", "key", key, "error", err)
+			if seedErr := c.durable.Seed(key, c.peekHighWater(key)); seedErr != nil {
+				return 0, fmt.Errorf("failed to seed durable counter: %v", seedErr)
+			}
+			return c.durable.Next(key)
+		}
+		return 0, fmt.Errorf("failed to increment message counter: %v", err)
+	}
+
+	c.rememberHighWater(key, result)
+	return result, nil
+}
+
+// NextChatNumberBatch reserves a contiguous range of n chat numbers for a
+// chat application in a single round-trip, returning [start, end]. Falls
+// back to the durable counter, if enabled, when Redis is unreachable.
+func (c *Client) NextChatNumberBatch(chatApplicationID int64, n int64) (int64, int64, error) {
+	return c.allocateBatch(chatCounterKey(chatApplicationID), chatCounterHighWaterKey(chatApplicationID), n)
+}
+
+// NextMessageNumberBatch reserves a contiguous range of n message numbers
+// for a chat in a single round-trip, returning [start, end]. Falls back to
+// the durable counter, if enabled, when Redis is unreachable.
+func (c *Client) NextMessageNumberBatch(chatID int64, n int64) (int64, int64, error) {
+	return c.allocateBatch(messageCounterKey(chatID), messageCounterHighWaterKey(chatID), n)
+}
+
+func (c *Client) allocateBatch(counterKey, highWaterKey string, n int64) (int64, int64, error) {
+	if n <= 0 {
+		return 0, 0, fmt.Errorf("batch size must be positive, got %d", n)
+	}
+
+	var res interface{}
+	err := metrics.ObserveRedis("allocate_batch", func() error {
+		var runErr error
+		res, runErr = allocateBatchScript.Run(c.ctx, c.rdb, []string{counterKey, highWaterKey}, n, highWaterTTL.Milliseconds()).Result()
+		return runErr
+	})
+	if err != nil {
+		if d := c.durable; d != nil {
+			logging.Logger.Warn("redis unavailable, falling back to durable counter", "key", counterKey, "error", err)
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			current, getErr := d.get(counterKey)
+			if getErr != nil {
+				return 0, 0, getErr
+			}
+			if hw := c.peekHighWater(counterKey); hw > current {
+				current = hw
+			}
+			end, putErr := d.put(counterKey, current+n)
+			if putErr != nil {
+				return 0, 0, putErr
+			}
+			return current + 1, end, nil
+		}
+		return 0, 0, fmt.Errorf("failed to allocate number batch: %v", err)
+	}
+
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 2 {
+		return 0, 0, fmt.Errorf("unexpected allocate batch result: %v", res)
+	}
+
+	start, err := toInt64(vals[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := toInt64(vals[1])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	c.rememberHighWater(counterKey, end)
+	return start, end, nil
+}
+
+// SyncChatCounterFromDB bumps the chat counter up to at least currentMax,
+// so the counter never trails behind MySQL after a Sidekiq failure. It is
+// safe to call on every startup: syncing below the stored value is a no-op.
+func (c *Client) SyncChatCounterFromDB(ctx context.Context, chatApplicationID int64, currentMax int64) error {
+	return c.syncCounter(ctx, chatCounterKey(chatApplicationID), currentMax)
+}
+
+// SyncMessageCounterFromDB bumps the message counter up to at least
+// currentMax, so the counter never trails behind MySQL after a Sidekiq
+// failure.
+func (c *Client) SyncMessageCounterFromDB(ctx context.Context, chatID int64, currentMax int64) error {
+	return c.syncCounter(ctx, messageCounterKey(chatID), currentMax)
+}
+
+func (c *Client) syncCounter(ctx context.Context, counterKey string, currentMax int64) error {
+	res, err := syncCounterScript.Run(ctx, c.rdb, []string{counterKey}, currentMax).Result()
+	if err != nil {
+		return fmt.Errorf("failed to sync counter %s: %v", counterKey, err)
+	}
+
+	// The script returns the counter's value after the sync (whether or
+	// not it moved), so also carry it into the in-memory high-water mark
+	// and the durable fallback: if Redis goes away before this key is ever
+	// incremented again, the fallback should still pick up from MySQL's
+	// max rather than 0.
+	if synced, err := toInt64(res); err == nil {
+		c.rememberHighWater(counterKey, synced)
+		if c.durable != nil {
+			if seedErr := c.durable.Seed(counterKey, synced); seedErr != nil {
+				logging.Logger.Warn("failed to seed durable counter from DB reconciliation", "key", counterKey, "error", seedErr)
+			}
+		}
+	}
+
+	return nil
+}
+
+func toInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int64:
+		return n, nil
+	default:
+		return 0, fmt.Errorf("expected int64, got %T", v)
+	}
+}
@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	t.Cleanup(mr.Close)
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	return NewClient(rdb)
+}
+
+func TestNextChatNumberBatchReservesContiguousRange(t *testing.T) {
+	c := newTestClient(t)
+
+	start, end, err := c.NextChatNumberBatch(1, 5)
+	if err != nil {
+		t.Fatalf("NextChatNumberBatch: %v", err)
+	}
+	if start != 1 || end != 5 {
+		t.Fatalf("expected range [1, 5], got [%d, %d]", start, end)
+	}
+
+	start, end, err = c.NextChatNumberBatch(1, 3)
+	if err != nil {
+		t.Fatalf("NextChatNumberBatch: %v", err)
+	}
+	if start != 6 || end != 8 {
+		t.Fatalf("expected range [6, 8], got [%d, %d]", start, end)
+	}
+}
+
+func TestNextChatNumberBatchRejectsNonPositiveN(t *testing.T) {
+	c := newTestClient(t)
+
+	if _, _, err := c.NextChatNumberBatch(1, 0); err == nil {
+		t.Fatal("expected an error for a zero batch size, got nil")
+	}
+}
+
+func TestSyncChatCounterFromDBNeverMovesBackwards(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	if _, _, err := c.NextChatNumberBatch(1, 10); err != nil {
+		t.Fatalf("NextChatNumberBatch: %v", err)
+	}
+
+	// A sync below the current value must be a no-op.
+	if err := c.SyncChatCounterFromDB(ctx, 1, 3); err != nil {
+		t.Fatalf("SyncChatCounterFromDB: %v", err)
+	}
+	start, _, err := c.NextChatNumberBatch(1, 1)
+	if err != nil {
+		t.Fatalf("NextChatNumberBatch: %v", err)
+	}
+	if start != 11 {
+		t.Fatalf("sync moved the counter backwards: next number was %d, want 11", start)
+	}
+
+	// A sync above the current value must bump it forward.
+	if err := c.SyncChatCounterFromDB(ctx, 1, 100); err != nil {
+		t.Fatalf("SyncChatCounterFromDB: %v", err)
+	}
+	start, _, err = c.NextChatNumberBatch(1, 1)
+	if err != nil {
+		t.Fatalf("NextChatNumberBatch: %v", err)
+	}
+	if start != 101 {
+		t.Fatalf("expected counter synced to 100, next number was %d, want 101", start)
+	}
+}
+
+func TestNextChatNumberFallsBackToDurableCounterWhenRedisIsDown(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	t.Cleanup(mr.Close)
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	c := NewClient(rdb)
+	if err := c.EnableDurableCounters(t.TempDir()); err != nil {
+		t.Fatalf("EnableDurableCounters: %v", err)
+	}
+
+	mr.Close()
+
+	first, err := c.NextChatNumber(1)
+	if err != nil {
+		t.Fatalf("expected fallback to durable counter, got error: %v", err)
+	}
+	second, err := c.NextChatNumber(1)
+	if err != nil {
+		t.Fatalf("expected fallback to durable counter, got error: %v", err)
+	}
+	if second != first+1 {
+		t.Fatalf("expected durable counter to keep incrementing, got %d then %d", first, second)
+	}
+}
+
+// TestNextChatNumberFallsBackWithoutReplayingAlreadyIssuedNumbers covers a
+// Redis outage that happens mid-run, after numbers have already been
+// handed out: the durable fallback must pick up after the last number
+// Redis issued, not restart from 0 and collide with chats already created.
+func TestNextChatNumberFallsBackWithoutReplayingAlreadyIssuedNumbers(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	t.Cleanup(mr.Close)
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	c := NewClient(rdb)
+	if err := c.EnableDurableCounters(t.TempDir()); err != nil {
+		t.Fatalf("EnableDurableCounters: %v", err)
+	}
+
+	const issuedViaRedis = 50
+	var last int64
+	for i := 0; i < issuedViaRedis; i++ {
+		last, err = c.NextChatNumber(1)
+		if err != nil {
+			t.Fatalf("NextChatNumber: %v", err)
+		}
+	}
+	if last != issuedViaRedis {
+		t.Fatalf("expected Redis to have issued %d, got %d", issuedViaRedis, last)
+	}
+
+	mr.Close()
+
+	next, err := c.NextChatNumber(1)
+	if err != nil {
+		t.Fatalf("expected fallback to durable counter, got error: %v", err)
+	}
+	if next != issuedViaRedis+1 {
+		t.Fatalf("durable counter replayed already-issued numbers: expected %d, got %d", issuedViaRedis+1, next)
+	}
+}
+
+// TestDurableCounterFallsBackFromMySQLSyncedValue covers the boot-time
+// reconciliation path: if MySQL's persisted max is synced into Redis but
+// Redis then goes down before the counter is ever incremented again, the
+// durable fallback must still start after MySQL's max, not from 0.
+func TestDurableCounterFallsBackFromMySQLSyncedValue(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	t.Cleanup(mr.Close)
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	c := NewClient(rdb)
+	if err := c.EnableDurableCounters(t.TempDir()); err != nil {
+		t.Fatalf("EnableDurableCounters: %v", err)
+	}
+
+	if err := c.SyncChatCounterFromDB(context.Background(), 1, 75); err != nil {
+		t.Fatalf("SyncChatCounterFromDB: %v", err)
+	}
+
+	mr.Close()
+
+	next, err := c.NextChatNumber(1)
+	if err != nil {
+		t.Fatalf("expected fallback to durable counter, got error: %v", err)
+	}
+	if next != 76 {
+		t.Fatalf("durable counter ignored the MySQL-synced max: expected 76, got %d", next)
+	}
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// DurableCounters is a disk-backed fallback for sequential number
+// allocation, used when Redis is unreachable so a chat/message number can
+// still be generated instead of failing the request outright.
+type DurableCounters struct {
+	mu sync.Mutex
+	db *leveldb.DB
+}
+
+// NewDurableCounters opens (or creates) a LevelDB counter store at dir.
+func NewDurableCounters(dir string) (*DurableCounters, error) {
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open durable counters at %s: %v", dir, err)
+	}
+	return &DurableCounters{db: db}, nil
+}
+
+// Next reserves and persists the next value for key.
+func (d *DurableCounters) Next(key string) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	current, err := d.get(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return d.put(key, current+1)
+}
+
+// Seed bumps the stored value for key up to at least minValue, never
+// moving it backwards. Call this with the last known Redis (or MySQL)
+// value before falling back to Next, so a counter that already issued
+// numbers through Redis doesn't restart from 0 once Redis goes away.
+func (d *DurableCounters) Seed(key string, minValue int64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	current, err := d.get(key)
+	if err != nil {
+		return err
+	}
+	if minValue <= current {
+		return nil
+	}
+
+	_, err = d.put(key, minValue)
+	return err
+}
+
+func (d *DurableCounters) put(key string, value int64) (int64, error) {
+	if err := d.db.Put([]byte(key), []byte(fmt.Sprintf("%d", value)), nil); err != nil {
+		return 0, fmt.Errorf("failed to persist durable counter %s: %v", key, err)
+	}
+	return value, nil
+}
+
+func (d *DurableCounters) get(key string) (int64, error) {
+	val, err := d.db.Get([]byte(key), nil)
+	if err == leveldb.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read durable counter %s: %v", key, err)
+	}
+
+	var current int64
+	if _, err := fmt.Sscanf(string(val), "%d", &current); err != nil {
+		return 0, fmt.Errorf("corrupt durable counter %s: %v", key, err)
+	}
+	return current, nil
+}
+
+// Close closes the underlying LevelDB handle.
+func (d *DurableCounters) Close() error {
+	return d.db.Close()
+}
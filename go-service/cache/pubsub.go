@@ -0,0 +1,25 @@
+package cache
+
+import (
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ChatMessagesChannel returns the Redis pub/sub channel a chat's new
+// messages are published on.
+func ChatMessagesChannel(chatID int64) string {
+	return fmt.Sprintf("chat:%d:messages", chatID)
+}
+
+// PublishChatMessage publishes an already-encoded message broadcast to the
+// given chat's channel, for the WebSocket hub to fan out.
+func (c *Client) PublishChatMessage(chatID int64, payload []byte) error {
+	return c.rdb.Publish(c.ctx, ChatMessagesChannel(chatID), payload).Err()
+}
+
+// SubscribeChatMessages subscribes to a chat's message channel. Callers
+// own the returned PubSub and must Close it when done.
+func (c *Client) SubscribeChatMessages(chatID int64) *redis.PubSub {
+	return c.rdb.Subscribe(c.ctx, ChatMessagesChannel(chatID))
+}
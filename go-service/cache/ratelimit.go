@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/luciq/chat-go-service/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript computes and persists a token bucket's state in one
+// round-trip: refill from elapsed time, then decrement by one if a token
+// is available. It returns whether the request is allowed, the tokens
+// remaining, and how long (in ms) until the next token would be available.
+var tokenBucketScript = redis.NewScript(`
+local key = KEYS[1]
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+local bucket = redis.call('HMGET', key, 'tokens', 'last_refill')
+local tokens = tonumber(bucket[1])
+local lastRefill = tonumber(bucket[2])
+
+if tokens == nil then
+	tokens = capacity
+	lastRefill = now
+end
+
+local elapsed = math.max(0, now - lastRefill)
+tokens = math.min(capacity, tokens + elapsed * rate)
+
+local allowed = 0
+local retryAfterMs = 0
+if tokens >= 1 then
+	allowed = 1
+	tokens = tokens - 1
+else
+	retryAfterMs = math.ceil(((1 - tokens) / rate) * 1000)
+end
+
+redis.call('HMSET', key, 'tokens', tostring(tokens), 'last_refill', tostring(now))
+redis.call('EXPIRE', key, ttl)
+
+return {allowed, tostring(tokens), retryAfterMs}
+`)
+
+func rateLimitKey(applicationToken string) string {
+	return fmt.Sprintf("rate_limit:%s", applicationToken)
+}
+
+// TakeToken attempts to take one token from the bucket for
+// applicationToken, refilling it at rate tokens/sec up to capacity.
+func (c *Client) TakeToken(applicationToken string, capacity, rate float64) (allowed bool, remaining float64, retryAfterMs int64, err error) {
+	ttlSeconds := int64(capacity/rate) + 1
+
+	var res interface{}
+	err = metrics.ObserveRedis("token_bucket", func() error {
+		var runErr error
+		res, runErr = tokenBucketScript.Run(
+			c.ctx, c.rdb,
+			[]string{rateLimitKey(applicationToken)},
+			capacity, rate, float64(time.Now().UnixNano())/1e9, ttlSeconds,
+		).Result()
+		return runErr
+	})
+	if err != nil {
+		return false, 0, 0, fmt.Errorf("failed to evaluate token bucket: %v", err)
+	}
+
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 3 {
+		return false, 0, 0, fmt.Errorf("unexpected token bucket result: %v", res)
+	}
+
+	allowedVal, ok := vals[0].(int64)
+	if !ok {
+		return false, 0, 0, fmt.Errorf("unexpected allowed value: %v", vals[0])
+	}
+
+	remainingStr, ok := vals[1].(string)
+	if !ok {
+		return false, 0, 0, fmt.Errorf("unexpected remaining value: %v", vals[1])
+	}
+	if _, err := fmt.Sscanf(remainingStr, "%f", &remaining); err != nil {
+		return false, 0, 0, fmt.Errorf("failed to parse remaining tokens: %v", err)
+	}
+
+	retryAfterMs, ok = vals[2].(int64)
+	if !ok {
+		return false, 0, 0, fmt.Errorf("unexpected retry_after_ms value: %v", vals[2])
+	}
+
+	return allowedVal == 1, remaining, retryAfterMs, nil
+}
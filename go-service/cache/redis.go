@@ -2,71 +2,48 @@ package cache
 
 import (
 	"context"
-	"fmt"
-	"log"
-	"os"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// RedisClient and Ctx remain for packages (like queue) that talk to Redis
+// directly rather than through an injected Client.
 var RedisClient *redis.Client
 var Ctx = context.Background()
 
-// InitRedis initializes the Redis client connection
-func InitRedis() error {
-	redisURL := os.Getenv("REDIS_URL")
-	if redisURL == "" {
-		redisURL = "localhost:6379"
-	}
-
-	RedisClient = redis.NewClient(&redis.Options{
-		Addr:     redisURL,
-		Password: "", // no password
-		DB:       0,  // default DB
-	})
+var defaultClient *Client
 
-	// Test connection
-	_, err := RedisClient.Ping(Ctx).Result()
+// InitRedis initializes the process-wide default Client and returns it, so
+// main can hand it to handler constructors.
+func InitRedis() (*Client, error) {
+	c, err := NewClientFromEnv()
 	if err != nil {
-		return fmt.Errorf("failed to connect to Redis: %v", err)
+		return nil, err
 	}
 
-	log.Println("Redis connected successfully")
-	return nil
+	defaultClient = c
+	RedisClient = c.rdb
+	Ctx = c.ctx
+
+	return c, nil
 }
 
-// NextChatNumber generates the next sequential chat number for a chat application
-// Mimics Rails SequentialNumberService.next_chat_number
+// NextChatNumber delegates to the default Client, kept for callers that
+// haven't migrated to an injected Client yet.
 func NextChatNumber(chatApplicationID int64) (int64, error) {
-	key := fmt.Sprintf("chat_app:%d:chat_counter", chatApplicationID)
-
-	result, err := RedisClient.Incr(Ctx, key).Result()
-	if err != nil {
-		return 0, fmt.Errorf("failed to increment chat counter: %v", err)
-	}
-
-	log.Printf("Generated chat number %d for app %d", result, chatApplicationID)
-	return result, nil
+	return defaultClient.NextChatNumber(chatApplicationID)
 }
 
-// NextMessageNumber generates the next sequential message number for a chat
-// Mimics Rails SequentialNumberService.next_message_number
+// NextMessageNumber delegates to the default Client, kept for callers that
+// haven't migrated to an injected Client yet.
 func NextMessageNumber(chatID int64) (int64, error) {
-	key := fmt.Sprintf("chat:%d:message_counter", chatID)
-
-	result, err := RedisClient.Incr(Ctx, key).Result()
-	if err != nil {
-		return 0, fmt.Errorf("failed to increment message counter: %v", err)
-	}
-
-	log.Printf("Generated message number %d for chat %d", result, chatID)
-	return result, nil
+	return defaultClient.NextMessageNumber(chatID)
 }
 
-// CloseRedis closes the Redis connection
+// CloseRedis closes the default Client's Redis connection.
 func CloseRedis() error {
-	if RedisClient != nil {
-		return RedisClient.Close()
+	if defaultClient != nil {
+		return defaultClient.Close()
 	}
 	return nil
 }
@@ -3,10 +3,11 @@ package db
 import (
 	"database/sql"
 	"fmt"
-	"log"
 	"os"
 
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/luciq/chat-go-service/logging"
+	"github.com/luciq/chat-go-service/metrics"
 )
 
 var DB *sql.DB
@@ -33,14 +34,16 @@ func InitDB() error {
 		return fmt.Errorf("failed to ping database: %v", err)
 	}
 
-	log.Println("MySQL connected successfully")
+	logging.Logger.Info("MySQL connected successfully")
 	return nil
 }
 
 // GetChatApplicationID gets the chat application ID by token
 func GetChatApplicationID(token string) (int64, error) {
 	var id int64
-	err := DB.QueryRow("SELECT id FROM chat_applications WHERE token = ?", token).Scan(&id)
+	err := metrics.ObserveMySQL("get_chat_application_id", func() error {
+		return DB.QueryRow("SELECT id FROM chat_applications WHERE token = ?", token).Scan(&id)
+	})
 	if err == sql.ErrNoRows {
 		return 0, fmt.Errorf("chat application not found")
 	}
@@ -54,7 +57,9 @@ func GetChatApplicationID(token string) (int64, error) {
 func GetChatID(chatApplicationID int64, chatNumber int64) (int64, error) {
 	var id int64
 	query := "SELECT id FROM chats WHERE chat_application_id = ? AND number = ?"
-	err := DB.QueryRow(query, chatApplicationID, chatNumber).Scan(&id)
+	err := metrics.ObserveMySQL("get_chat_id", func() error {
+		return DB.QueryRow(query, chatApplicationID, chatNumber).Scan(&id)
+	})
 	if err == sql.ErrNoRows {
 		return 0, fmt.Errorf("chat not found")
 	}
@@ -64,6 +69,74 @@ func GetChatID(chatApplicationID int64, chatNumber int64) (int64, error) {
 	return id, nil
 }
 
+// ChatCounterState is one chat application's highest persisted chat
+// number, used to reconcile the Redis chat counter on startup.
+type ChatCounterState struct {
+	ChatApplicationID int64
+	MaxNumber         int64
+}
+
+// GetMaxChatNumbers returns the highest persisted chat number per chat
+// application, for reconciling the Redis counter against rows Sidekiq
+// already wrote.
+func GetMaxChatNumbers() ([]ChatCounterState, error) {
+	var states []ChatCounterState
+	err := metrics.ObserveMySQL("get_max_chat_numbers", func() error {
+		rows, err := DB.Query("SELECT chat_application_id, MAX(number) FROM chats GROUP BY chat_application_id")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var s ChatCounterState
+			if err := rows.Scan(&s.ChatApplicationID, &s.MaxNumber); err != nil {
+				return err
+			}
+			states = append(states, s)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("database query error: %v", err)
+	}
+	return states, nil
+}
+
+// MessageCounterState is one chat's highest persisted message number, used
+// to reconcile the Redis message counter on startup.
+type MessageCounterState struct {
+	ChatID    int64
+	MaxNumber int64
+}
+
+// GetMaxMessageNumbers returns the highest persisted message number per
+// chat, for reconciling the Redis counter against rows Sidekiq already
+// wrote.
+func GetMaxMessageNumbers() ([]MessageCounterState, error) {
+	var states []MessageCounterState
+	err := metrics.ObserveMySQL("get_max_message_numbers", func() error {
+		rows, err := DB.Query("SELECT chat_id, MAX(number) FROM messages GROUP BY chat_id")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var s MessageCounterState
+			if err := rows.Scan(&s.ChatID, &s.MaxNumber); err != nil {
+				return err
+			}
+			states = append(states, s)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("database query error: %v", err)
+	}
+	return states, nil
+}
+
 // CloseDB closes the database connection
 func CloseDB() error {
 	if DB != nil {
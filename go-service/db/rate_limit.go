@@ -0,0 +1,50 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/luciq/chat-go-service/metrics"
+)
+
+// Defaults applied when a chat_applications row hasn't set its own quota.
+const (
+	DefaultRateLimitCapacity     = 60.0
+	DefaultRateLimitRefillPerSec = 1.0
+)
+
+// RateLimitConfig is a chat application's token-bucket quota.
+type RateLimitConfig struct {
+	Capacity     float64
+	RefillPerSec float64
+}
+
+// GetRateLimitConfig loads the rate limit config for a chat application by
+// token, falling back to the package defaults for any NULL column.
+func GetRateLimitConfig(token string) (RateLimitConfig, error) {
+	var capacity, refill sql.NullFloat64
+
+	query := "SELECT rate_limit_capacity, rate_limit_refill_per_sec FROM chat_applications WHERE token = ?"
+	err := metrics.ObserveMySQL("get_rate_limit_config", func() error {
+		return DB.QueryRow(query, token).Scan(&capacity, &refill)
+	})
+	if err == sql.ErrNoRows {
+		return RateLimitConfig{}, fmt.Errorf("chat application not found")
+	}
+	if err != nil {
+		return RateLimitConfig{}, fmt.Errorf("database query error: %v", err)
+	}
+
+	cfg := RateLimitConfig{
+		Capacity:     DefaultRateLimitCapacity,
+		RefillPerSec: DefaultRateLimitRefillPerSec,
+	}
+	if capacity.Valid {
+		cfg.Capacity = capacity.Float64
+	}
+	if refill.Valid {
+		cfg.RefillPerSec = refill.Float64
+	}
+
+	return cfg, nil
+}
@@ -2,27 +2,39 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/luciq/chat-go-service/cache"
 	"github.com/luciq/chat-go-service/db"
+	"github.com/luciq/chat-go-service/logging"
 	"github.com/luciq/chat-go-service/models"
 	"github.com/luciq/chat-go-service/queue"
 )
 
+// ChatHandler holds the dependencies used by chat endpoints. It is built
+// with an injected cache.Client so tests can point it at a miniredis
+// instance instead of a live Redis server.
+type ChatHandler struct {
+	Cache *cache.Client
+}
+
+// NewChatHandler builds a ChatHandler backed by the given cache.Client.
+func NewChatHandler(cacheClient *cache.Client) *ChatHandler {
+	return &ChatHandler{Cache: cacheClient}
+}
+
 // CreateChat handles POST /api/v1/chat_applications/:token/chats
-func CreateChat(w http.ResponseWriter, r *http.Request) {
+func (h *ChatHandler) CreateChat(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	token := vars["token"]
-
-	log.Printf("CreateChat request for token: %s", token)
+	logging.SetToken(r.Context(), token)
+	logger := logging.FromContext(r.Context())
 
 	// 1. Validate chat application exists
 	chatAppID, err := db.GetChatApplicationID(token)
 	if err != nil {
-		log.Printf("Chat application not found: %v", err)
+		logger.Warn("chat application not found", "error", err)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(models.ErrorResponse{
 			Error: "ChatApplication not found",
@@ -30,10 +42,11 @@ func CreateChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 2. Get next chat number from Redis (atomic)
-	chatNumber, err := cache.NextChatNumber(chatAppID)
+	// 2. Reserve the next chat number from Redis in one round-trip via the
+	// batch allocator (batch size 1: one HTTP request creates one chat).
+	chatNumber, _, err := h.Cache.NextChatNumberBatch(chatAppID, 1)
 	if err != nil {
-		log.Printf("Failed to generate chat number: %v", err)
+		logger.Error("failed to generate chat number", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(models.ErrorResponse{
 			Error: "Failed to generate chat number",
@@ -44,7 +57,7 @@ func CreateChat(w http.ResponseWriter, r *http.Request) {
 	// 3. Queue Sidekiq job to persist chat
 	err = queue.EnqueueCreateChatJob(chatAppID, chatNumber)
 	if err != nil {
-		log.Printf("Failed to enqueue CreateChatJob: %v", err)
+		logger.Error("failed to enqueue CreateChatJob", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(models.ErrorResponse{
 			Error: "Failed to create chat",
@@ -59,5 +72,5 @@ func CreateChat(w http.ResponseWriter, r *http.Request) {
 		MessagesCount: 0,
 	})
 
-	log.Printf("Chat created: app_id=%d, number=%d", chatAppID, chatNumber)
+	logger.Info("chat created", "chat_app_id", chatAppID, "number", chatNumber)
 }
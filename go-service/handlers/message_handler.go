@@ -2,22 +2,39 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/luciq/chat-go-service/cache"
 	"github.com/luciq/chat-go-service/db"
+	"github.com/luciq/chat-go-service/logging"
 	"github.com/luciq/chat-go-service/models"
 	"github.com/luciq/chat-go-service/queue"
+	"github.com/luciq/chat-go-service/ws"
 )
 
+// MessageHandler holds the dependencies used by message endpoints. It is
+// built with an injected cache.Client so tests can point it at a
+// miniredis instance instead of a live Redis server.
+type MessageHandler struct {
+	Cache   *cache.Client
+	userIDs *ws.UserIDCalculator
+}
+
+// NewMessageHandler builds a MessageHandler backed by the given cache.Client.
+func NewMessageHandler(cacheClient *cache.Client) *MessageHandler {
+	return &MessageHandler{Cache: cacheClient, userIDs: ws.NewUserIDCalculator()}
+}
+
 // CreateMessage handles POST /api/v1/chat_applications/:token/chats/:number/messages
-func CreateMessage(w http.ResponseWriter, r *http.Request) {
+func (h *MessageHandler) CreateMessage(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	token := vars["token"]
 	chatNumberStr := vars["number"]
+	logging.SetToken(r.Context(), token)
+	logger := logging.FromContext(r.Context())
 
 	chatNumber, err := strconv.ParseInt(chatNumberStr, 10, 64)
 	if err != nil {
@@ -28,12 +45,10 @@ func CreateMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("CreateMessage request for token: %s, chat: %d", token, chatNumber)
-
 	// 1. Parse request body
 	var req models.CreateMessageRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("Failed to decode request: %v", err)
+		logger.Warn("failed to decode request", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(models.ErrorResponse{
 			Error: "Invalid request body",
@@ -53,7 +68,7 @@ func CreateMessage(w http.ResponseWriter, r *http.Request) {
 	// 3. Validate chat application exists
 	chatAppID, err := db.GetChatApplicationID(token)
 	if err != nil {
-		log.Printf("Chat application not found: %v", err)
+		logger.Warn("chat application not found", "error", err)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(models.ErrorResponse{
 			Error: "ChatApplication not found",
@@ -64,18 +79,21 @@ func CreateMessage(w http.ResponseWriter, r *http.Request) {
 	// 4. Validate chat exists
 	chatID, err := db.GetChatID(chatAppID, chatNumber)
 	if err != nil {
-		log.Printf("Chat not found: %v", err)
+		logger.Warn("chat not found", "error", err)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(models.ErrorResponse{
 			Error: "Chat not found",
 		})
 		return
 	}
+	logging.SetChatID(r.Context(), chatID)
+	logger = logging.FromContext(r.Context())
 
-	// 5. Get next message number from Redis (atomic)
-	messageNumber, err := cache.NextMessageNumber(chatID)
+	// 5. Reserve the next message number from Redis in one round-trip via
+	// the batch allocator (batch size 1: one HTTP request creates one message).
+	messageNumber, _, err := h.Cache.NextMessageNumberBatch(chatID, 1)
 	if err != nil {
-		log.Printf("Failed to generate message number: %v", err)
+		logger.Error("failed to generate message number", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(models.ErrorResponse{
 			Error: "Failed to generate message number",
@@ -86,7 +104,7 @@ func CreateMessage(w http.ResponseWriter, r *http.Request) {
 	// 6. Queue Sidekiq job to persist message
 	err = queue.EnqueueCreateMessageJob(chatID, messageNumber, req.Message.Body)
 	if err != nil {
-		log.Printf("Failed to enqueue CreateMessageJob: %v", err)
+		logger.Error("failed to enqueue CreateMessageJob", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(models.ErrorResponse{
 			Error: "Failed to create message",
@@ -94,11 +112,27 @@ func CreateMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 7. Return response immediately (async processing)
+	// 7. Publish to the chat's stream so connected WebSocket clients see
+	// it immediately; a failure here is non-fatal since Rails remains the
+	// source of truth for the message itself.
+	broadcast := models.MessageBroadcast{
+		ChatID:    chatID,
+		Number:    messageNumber,
+		Body:      req.Message.Body,
+		SenderID:  h.userIDs.Calculate(r, token),
+		CreatedAt: time.Now().UTC(),
+	}
+	if payload, err := json.Marshal(broadcast); err != nil {
+		logger.Error("failed to marshal message broadcast", "error", err)
+	} else if err := h.Cache.PublishChatMessage(chatID, payload); err != nil {
+		logger.Error("failed to publish message", "error", err)
+	}
+
+	// 8. Return response immediately (async processing)
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(models.CreateMessageResponse{
 		Number: messageNumber,
 	})
 
-	log.Printf("Message created: chat_id=%d, number=%d, body=%s", chatID, messageNumber, req.Message.Body)
+	logger.Info("message created", "number", messageNumber)
 }
@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+// Logger is the process-wide structured logger, emitting JSON to stdout.
+var Logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+type ctxKey int
+
+const requestContextKey ctxKey = iota
+
+// requestContext accumulates the fields a request's access log line
+// should carry. It's attached to the request context by
+// middleware.LoggingMiddleware and filled in as handlers resolve the
+// chat application token and chat id.
+type requestContext struct {
+	RequestID string
+	Token     string
+	ChatID    int64
+}
+
+// NewContext attaches a fresh requestContext, seeded with requestID, to ctx.
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestContextKey, &requestContext{RequestID: requestID})
+}
+
+func fromCtx(ctx context.Context) *requestContext {
+	rc, _ := ctx.Value(requestContextKey).(*requestContext)
+	return rc
+}
+
+// RequestID returns the request id attached to ctx, or "" if none.
+func RequestID(ctx context.Context) string {
+	if rc := fromCtx(ctx); rc != nil {
+		return rc.RequestID
+	}
+	return ""
+}
+
+// SetToken records the chat application token for ctx's access log line.
+func SetToken(ctx context.Context, token string) {
+	if rc := fromCtx(ctx); rc != nil {
+		rc.Token = token
+	}
+}
+
+// SetChatID records the resolved chat id for ctx's access log line.
+func SetChatID(ctx context.Context, chatID int64) {
+	if rc := fromCtx(ctx); rc != nil {
+		rc.ChatID = chatID
+	}
+}
+
+// FromContext returns a logger enriched with every field recorded on ctx
+// so far (request_id, and once resolved, token/chat_id).
+func FromContext(ctx context.Context) *slog.Logger {
+	rc := fromCtx(ctx)
+	if rc == nil {
+		return Logger
+	}
+
+	l := Logger.With("request_id", rc.RequestID)
+	if rc.Token != "" {
+		l = l.With("token", rc.Token)
+	}
+	if rc.ChatID != 0 {
+		l = l.With("chat_id", rc.ChatID)
+	}
+	return l
+}
@@ -0,0 +1,15 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// NewRequestID generates a random id for the X-Request-Id header.
+func NewRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -13,24 +14,56 @@ import (
 	"github.com/luciq/chat-go-service/cache"
 	"github.com/luciq/chat-go-service/db"
 	"github.com/luciq/chat-go-service/handlers"
+	"github.com/luciq/chat-go-service/metrics"
 	"github.com/luciq/chat-go-service/middleware"
+	"github.com/luciq/chat-go-service/queue"
+	"github.com/luciq/chat-go-service/ws"
 )
 
 func main() {
 	log.Println("Starting Go Chat Service...")
 
 	// Initialize Redis
-	if err := cache.InitRedis(); err != nil {
+	cacheClient, err := cache.InitRedis()
+	if err != nil {
 		log.Fatalf("Failed to initialize Redis: %v", err)
 	}
 	defer cache.CloseRedis()
 
+	// When running with a durable queue, also fall back to an on-disk
+	// counter so number allocation survives a Redis outage.
+	if queueType := os.Getenv("QUEUE_TYPE"); queueType == "hybrid" || queueType == "leveldb" {
+		dir := os.Getenv("QUEUE_DIR")
+		if dir == "" {
+			dir = "./queue-wal"
+		}
+		if err := cacheClient.EnableDurableCounters(dir + "/counters"); err != nil {
+			log.Fatalf("Failed to enable durable counters: %v", err)
+		}
+	}
+
 	// Initialize MySQL
 	if err := db.InitDB(); err != nil {
 		log.Fatalf("Failed to initialize MySQL: %v", err)
 	}
 	defer db.CloseDB()
 
+	// Bump the Redis counters up to MySQL's persisted max, so a counter that
+	// fell behind after a crashed Sidekiq job doesn't hand out numbers that
+	// collide with rows Sidekiq already wrote. Non-fatal: if Redis is still
+	// down at boot, per-request allocation falls back to the durable counter
+	// when it is enabled. Reconciliation runs only here, once at startup; it
+	// is not retried after Redis recovers.
+	if err := reconcileCounters(cacheClient); err != nil {
+		log.Printf("Warning: failed to reconcile counters from MySQL: %v", err)
+	}
+
+	// Initialize the message bus (Sidekiq/Redis or RabbitMQ, per MESSAGE_BUS)
+	if err := queue.Init(); err != nil {
+		log.Fatalf("Failed to initialize message bus: %v", err)
+	}
+	defer queue.Close()
+
 	// Create router
 	router := mux.NewRouter()
 
@@ -38,6 +71,7 @@ func main() {
 	router.Use(middleware.LoggingMiddleware)
 	router.Use(middleware.RecoveryMiddleware)
 	router.Use(middleware.CORSMiddleware)
+	router.Use(metrics.Instrument)
 
 	// Health check endpoint
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -45,14 +79,25 @@ func main() {
 		w.Write([]byte(`{"status":"healthy"}`))
 	}).Methods("GET")
 
+	// Prometheus metrics endpoint
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+
 	// API routes - matching Rails pattern
 	apiRouter := router.PathPrefix("/api/v1").Subrouter()
 
+	chatHandler := handlers.NewChatHandler(cacheClient)
+	messageHandler := handlers.NewMessageHandler(cacheClient)
+	streamHandler := ws.NewStreamHandler(ws.NewHub(cacheClient))
+	rateLimiter := middleware.NewRateLimiter(cacheClient)
+
 	// POST /api/v1/chat_applications/:token/chats
-	apiRouter.HandleFunc("/chat_applications/{token}/chats", handlers.CreateChat).Methods("POST")
+	apiRouter.Handle("/chat_applications/{token}/chats", rateLimiter.Middleware(http.HandlerFunc(chatHandler.CreateChat))).Methods("POST")
 
 	// POST /api/v1/chat_applications/:token/chats/:number/messages
-	apiRouter.HandleFunc("/chat_applications/{token}/chats/{number}/messages", handlers.CreateMessage).Methods("POST")
+	apiRouter.Handle("/chat_applications/{token}/chats/{number}/messages", rateLimiter.Middleware(http.HandlerFunc(messageHandler.CreateMessage))).Methods("POST")
+
+	// GET /api/v1/chat_applications/:token/chats/:number/stream
+	apiRouter.HandleFunc("/chat_applications/{token}/chats/{number}/stream", streamHandler.Stream).Methods("GET")
 
 	// Get port from environment or default to 8080
 	port := os.Getenv("PORT")
@@ -75,7 +120,9 @@ func main() {
 		log.Println("Endpoints:")
 		log.Println("  POST /api/v1/chat_applications/:token/chats")
 		log.Println("  POST /api/v1/chat_applications/:token/chats/:number/messages")
+		log.Println("  GET  /api/v1/chat_applications/:token/chats/:number/stream")
 		log.Println("  GET  /health")
+		log.Println("  GET  /metrics")
 
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server failed: %v", err)
@@ -99,3 +146,30 @@ func main() {
 
 	log.Println("Server exited gracefully")
 }
+
+// reconcileCounters bumps every chat/message counter up to at least MySQL's
+// persisted max, so the cache never hands out a number that collides with
+// a row Sidekiq already wrote before a crash.
+func reconcileCounters(c *cache.Client) error {
+	chatStates, err := db.GetMaxChatNumbers()
+	if err != nil {
+		return fmt.Errorf("failed to load chat counter state: %v", err)
+	}
+	for _, s := range chatStates {
+		if err := c.SyncChatCounterFromDB(context.Background(), s.ChatApplicationID, s.MaxNumber); err != nil {
+			return err
+		}
+	}
+
+	messageStates, err := db.GetMaxMessageNumbers()
+	if err != nil {
+		return fmt.Errorf("failed to load message counter state: %v", err)
+	}
+	for _, s := range messageStates {
+		if err := c.SyncMessageCounterFromDB(context.Background(), s.ChatID, s.MaxNumber); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_service_http_requests_total",
+		Help: "Total HTTP requests by route and status.",
+	}, []string{"route", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "chat_service_http_request_duration_seconds",
+		Help:    "HTTP request latency by route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "status"})
+
+	redisOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "chat_service_redis_operation_duration_seconds",
+		Help:    "Redis operation latency by operation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	redisOperationErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_service_redis_operation_errors_total",
+		Help: "Redis operation errors by operation.",
+	}, []string{"operation"})
+
+	mysqlQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "chat_service_mysql_query_duration_seconds",
+		Help:    "MySQL query latency by query.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"query"})
+
+	mysqlQueryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_service_mysql_query_errors_total",
+		Help: "MySQL query errors by query.",
+	}, []string{"query"})
+
+	queueEnqueueDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "chat_service_queue_enqueue_duration_seconds",
+		Help:    "Queue enqueue latency by backend.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"backend"})
+
+	queueEnqueueErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_service_queue_enqueue_errors_total",
+		Help: "Queue enqueue errors by backend.",
+	}, []string{"backend"})
+)
+
+// Handler serves the Prometheus /metrics endpoint.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// statusRecorder wraps http.ResponseWriter to capture the response status
+// for route+status metrics.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Hijack passes through to the underlying ResponseWriter's Hijacker so the
+// /stream route can still upgrade to a WebSocket connection through this
+// middleware. Embedding http.ResponseWriter doesn't satisfy http.Hijacker
+// on its own since that's an interface, not the concrete writer.
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("underlying ResponseWriter does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
+
+// Flush passes through to the underlying ResponseWriter's Flusher, if any.
+func (w *statusRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Instrument wraps an http.Handler to record request counts and latency
+// by route and status. Register it on a mux.Router (directly or via
+// router.Use) so mux.CurrentRoute can resolve the route template.
+func Instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+		duration := time.Since(start).Seconds()
+
+		route := routeTemplate(r)
+		status := strconv.Itoa(rec.status)
+
+		httpRequestsTotal.WithLabelValues(route, status).Inc()
+		httpRequestDuration.WithLabelValues(route, status).Observe(duration)
+	})
+}
+
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return r.URL.Path
+}
+
+// ObserveRedis times fn and records it under the "redis" operation
+// histogram/error counter, e.g. metrics.ObserveRedis("incr", func() error { ... }).
+func ObserveRedis(operation string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	redisOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		redisOperationErrors.WithLabelValues(operation).Inc()
+	}
+	return err
+}
+
+// ObserveMySQL times fn and records it under the "mysql" query
+// histogram/error counter.
+func ObserveMySQL(query string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	mysqlQueryDuration.WithLabelValues(query).Observe(time.Since(start).Seconds())
+	if err != nil {
+		mysqlQueryErrors.WithLabelValues(query).Inc()
+	}
+	return err
+}
+
+// ObserveQueueEnqueue times fn and records it under the "queue" backend
+// histogram/error counter.
+func ObserveQueueEnqueue(backend string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	queueEnqueueDuration.WithLabelValues(backend).Observe(time.Since(start).Seconds())
+	if err != nil {
+		queueEnqueueErrors.WithLabelValues(backend).Inc()
+	}
+	return err
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// hijackableRecorder is an httptest.ResponseRecorder that also implements
+// http.Hijacker, the way the real http.ResponseWriter passed to a handler
+// behind a hijacking-capable server does.
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	server, _ := net.Pipe()
+	return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+}
+
+func TestStatusRecorderPassesThroughHijack(t *testing.T) {
+	rec := &hijackableRecorder{ResponseRecorder: httptest.NewRecorder()}
+	wrapped := &statusRecorder{ResponseWriter: rec, status: http.StatusOK}
+
+	hijacker, ok := http.ResponseWriter(wrapped).(http.Hijacker)
+	if !ok {
+		t.Fatal("statusRecorder does not implement http.Hijacker, e.g. gorilla/websocket.Upgrader.Upgrade would fail")
+	}
+
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		t.Fatalf("Hijack: %v", err)
+	}
+	defer conn.Close()
+
+	if !rec.hijacked {
+		t.Fatal("Hijack did not reach the underlying ResponseWriter")
+	}
+}
@@ -1,21 +1,80 @@
 package middleware
 
 import (
-	"log"
+	"bufio"
+	"fmt"
+	"net"
 	"net/http"
 	"time"
+
+	"github.com/luciq/chat-go-service/logging"
 )
 
-// LoggingMiddleware logs HTTP requests
+// responseRecorder wraps http.ResponseWriter to capture the status code
+// and bytes written for the access log line.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Hijack passes through to the underlying ResponseWriter's Hijacker so the
+// /stream route can still upgrade to a WebSocket connection through this
+// middleware. Embedding http.ResponseWriter doesn't satisfy http.Hijacker
+// on its own since that's an interface, not the concrete writer.
+func (w *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("underlying ResponseWriter does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
+
+// Flush passes through to the underlying ResponseWriter's Flusher, if any.
+func (w *responseRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// LoggingMiddleware generates/propagates an X-Request-Id header, attaches
+// it to the request context, and emits a structured access log line with
+// the request's status, size, and latency once the handler returns.
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
+		requestID := r.Header.Get("X-Request-Id")
+		if requestID == "" {
+			requestID = logging.NewRequestID()
+		}
+		w.Header().Set("X-Request-Id", requestID)
 
-		log.Printf("[%s] %s %s", r.Method, r.URL.Path, r.RemoteAddr)
+		ctx := logging.NewContext(r.Context(), requestID)
+		r = r.WithContext(ctx)
 
-		next.ServeHTTP(w, r)
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+		latency := time.Since(start)
 
-		log.Printf("Request completed in %v", time.Since(start))
+		logging.FromContext(ctx).Info("http_request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"latency_ms", latency.Milliseconds(),
+		)
 	})
 }
 
@@ -24,7 +83,7 @@ func RecoveryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("Panic recovered: %v", err)
+				logging.FromContext(r.Context()).Error("panic recovered", "error", err)
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			}
 		}()
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// hijackableRecorder is an httptest.ResponseRecorder that also implements
+// http.Hijacker, the way the real http.ResponseWriter passed to a handler
+// behind a hijacking-capable server does.
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	server, _ := net.Pipe()
+	return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+}
+
+func TestResponseRecorderPassesThroughHijack(t *testing.T) {
+	rec := &hijackableRecorder{ResponseRecorder: httptest.NewRecorder()}
+	wrapped := &responseRecorder{ResponseWriter: rec, status: http.StatusOK}
+
+	hijacker, ok := http.ResponseWriter(wrapped).(http.Hijacker)
+	if !ok {
+		t.Fatal("responseRecorder does not implement http.Hijacker, e.g. gorilla/websocket.Upgrader.Upgrade would fail")
+	}
+
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		t.Fatalf("Hijack: %v", err)
+	}
+	defer conn.Close()
+
+	if !rec.hijacked {
+		t.Fatal("Hijack did not reach the underlying ResponseWriter")
+	}
+}
+
+func TestResponseRecorderHijackErrorsWhenUnderlyingWriterCannot(t *testing.T) {
+	wrapped := &responseRecorder{ResponseWriter: httptest.NewRecorder(), status: http.StatusOK}
+
+	if _, ok := http.ResponseWriter(wrapped).(http.Hijacker); !ok {
+		t.Fatal("responseRecorder should still satisfy http.Hijacker even if the underlying writer can't hijack")
+	}
+
+	if _, _, err := wrapped.Hijack(); err == nil {
+		t.Fatal("expected an error when the underlying ResponseWriter doesn't support hijacking")
+	}
+}
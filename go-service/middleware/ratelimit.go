@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/luciq/chat-go-service/cache"
+	"github.com/luciq/chat-go-service/db"
+	"github.com/luciq/chat-go-service/logging"
+	"github.com/luciq/chat-go-service/models"
+)
+
+// rateLimitConfigCacheTTL bounds how long a chat application's rate limit
+// config is cached in-process before the next request re-checks MySQL.
+const rateLimitConfigCacheTTL = 30 * time.Second
+
+// RateLimiter enforces a distributed token-bucket quota per {token} path
+// variable, so each chat application has an independent budget.
+// Capacity/refill rate are loaded from MySQL and cached in-process to
+// avoid a DB hit on every request.
+type RateLimiter struct {
+	cache *cache.Client
+
+	mu          sync.Mutex
+	configCache map[string]cachedRateLimitConfig
+}
+
+type cachedRateLimitConfig struct {
+	config    db.RateLimitConfig
+	expiresAt time.Time
+}
+
+// NewRateLimiter builds a RateLimiter backed by the given cache.Client.
+func NewRateLimiter(cacheClient *cache.Client) *RateLimiter {
+	return &RateLimiter{
+		cache:       cacheClient,
+		configCache: make(map[string]cachedRateLimitConfig),
+	}
+}
+
+// Middleware enforces the token bucket for the request's {token} path
+// variable, returning 429 with an ErrorResponse once it's empty.
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := mux.Vars(r)["token"]
+		if token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		logging.SetToken(r.Context(), token)
+
+		cfg, err := rl.configFor(token)
+		if err != nil {
+			logging.FromContext(r.Context()).Warn("rate limit config lookup failed", "token", token, "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, remaining, retryAfterMs, err := rl.cache.TakeToken(token, cfg.Capacity, cfg.RefillPerSec)
+		if err != nil {
+			logging.FromContext(r.Context()).Warn("rate limiter error, allowing request", "token", token, "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(int64(cfg.Capacity), 10))
+		w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(int64(remaining), 10))
+
+		if !allowed {
+			retryAfterSec := (retryAfterMs + 999) / 1000
+			w.Header().Set("Retry-After", strconv.FormatInt(retryAfterSec, 10))
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error: "Rate limit exceeded",
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// configFor returns the cached rate limit config for token, refreshing it
+// from MySQL once rateLimitConfigCacheTTL has elapsed.
+func (rl *RateLimiter) configFor(token string) (db.RateLimitConfig, error) {
+	rl.mu.Lock()
+	cached, ok := rl.configCache[token]
+	rl.mu.Unlock()
+
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.config, nil
+	}
+
+	cfg, err := db.GetRateLimitConfig(token)
+	if err != nil {
+		return db.RateLimitConfig{}, err
+	}
+
+	rl.mu.Lock()
+	rl.configCache[token] = cachedRateLimitConfig{
+		config:    cfg,
+		expiresAt: time.Now().Add(rateLimitConfigCacheTTL),
+	}
+	rl.mu.Unlock()
+
+	return cfg, nil
+}
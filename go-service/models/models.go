@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // CreateChatResponse represents the response when creating a chat
 type CreateChatResponse struct {
 	Number        int64 `json:"number"`
@@ -22,3 +24,14 @@ type CreateMessageResponse struct {
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
+
+// MessageBroadcast is the payload fanned out over the chat WebSocket
+// stream whenever a new message is created. SenderID lets the client that
+// posted the message recognize its own echo.
+type MessageBroadcast struct {
+	ChatID    int64     `json:"chat_id"`
+	Number    int64     `json:"number"`
+	Body      string    `json:"body"`
+	SenderID  string    `json:"sender_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,88 @@
+package queue
+
+import (
+	"fmt"
+	"os"
+)
+
+// MessageBus abstracts the transport used to dispatch ActiveJob payloads to
+// the Rails side. Implementations must be safe for concurrent use.
+type MessageBus interface {
+	// Enqueue dispatches a single job payload.
+	Enqueue(payload ActiveJobPayload) error
+	// Close releases any underlying connections.
+	Close() error
+}
+
+// bus is the process-wide MessageBus selected at Init time.
+var bus MessageBus
+
+// Init selects and connects the MessageBus backend based on the
+// MESSAGE_BUS environment variable ("sidekiq" or "rabbitmq"), then wraps
+// it per QUEUE_TYPE ("redis", "leveldb", or "hybrid") and QUEUE_DIR. It
+// defaults to "sidekiq"/"redis" to preserve existing behavior when unset.
+func Init() error {
+	backend := os.Getenv("MESSAGE_BUS")
+	if backend == "" {
+		backend = "sidekiq"
+	}
+
+	var primary MessageBus
+	switch backend {
+	case "sidekiq":
+		primary = NewSidekiqBus()
+	case "rabbitmq":
+		rmqBus, err := NewRabbitMQBus()
+		if err != nil {
+			return fmt.Errorf("failed to initialize rabbitmq bus: %v", err)
+		}
+		primary = rmqBus
+	default:
+		return fmt.Errorf("unknown MESSAGE_BUS %q (expected sidekiq or rabbitmq)", backend)
+	}
+
+	queueType := os.Getenv("QUEUE_TYPE")
+	if queueType == "" {
+		queueType = "redis"
+	}
+
+	switch queueType {
+	case "redis":
+		bus = primary
+	case "hybrid", "leveldb":
+		dir := os.Getenv("QUEUE_DIR")
+		if dir == "" {
+			dir = defaultQueueDir
+		}
+		durableBus, err := NewDurableBus(primary, dir, queueType == "leveldb")
+		if err != nil {
+			return fmt.Errorf("failed to initialize durable queue: %v", err)
+		}
+		bus = durableBus
+	default:
+		return fmt.Errorf("unknown QUEUE_TYPE %q (expected redis, leveldb, or hybrid)", queueType)
+	}
+
+	return nil
+}
+
+// Close shuts down the active MessageBus, if one was initialized.
+func Close() error {
+	if bus != nil {
+		return bus.Close()
+	}
+	return nil
+}
+
+// routingKeyForJobClass derives a RabbitMQ routing key from an ActiveJob
+// class name, e.g. "CreateChatJob" -> "chat.create".
+func routingKeyForJobClass(jobClass string) string {
+	switch jobClass {
+	case "CreateChatJob":
+		return "chat.create"
+	case "CreateMessageJob":
+		return "message.create"
+	default:
+		return "job.unknown"
+	}
+}
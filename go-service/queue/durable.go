@@ -0,0 +1,103 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/luciq/chat-go-service/logging"
+)
+
+const (
+	defaultQueueDir     = "./queue-wal"
+	drainInitialBackoff = 1 * time.Second
+	drainMaxBackoff     = 30 * time.Second
+)
+
+// DurableBus wraps a primary MessageBus with a LevelDB write-ahead
+// buffer, so Redis/RabbitMQ being down doesn't drop jobs on the floor. A
+// background goroutine drains the buffer back into the primary bus once
+// it recovers, backing off exponentially while it stays down.
+//
+// In alwaysBuffer mode every job is written to disk first and drained
+// asynchronously, trading latency for the strongest durability guarantee.
+// Otherwise the primary bus is tried first and disk is only used as a
+// fallback on enqueue failure.
+type DurableBus struct {
+	primary      MessageBus
+	store        *levelDBStore
+	alwaysBuffer bool
+	stop         chan struct{}
+}
+
+// NewDurableBus wraps primary with a LevelDB WAL rooted at dir and starts
+// the background drain loop.
+func NewDurableBus(primary MessageBus, dir string, alwaysBuffer bool) (*DurableBus, error) {
+	store, err := openLevelDBStore(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &DurableBus{
+		primary:      primary,
+		store:        store,
+		alwaysBuffer: alwaysBuffer,
+		stop:         make(chan struct{}),
+	}
+
+	go b.drainLoop()
+	return b, nil
+}
+
+// Enqueue buffers to disk (alwaysBuffer mode) or tries the primary bus
+// first, falling back to disk so a primary outage doesn't drop the job.
+func (b *DurableBus) Enqueue(payload ActiveJobPayload) error {
+	if b.alwaysBuffer {
+		return b.store.Append(payload)
+	}
+
+	if err := b.primary.Enqueue(payload); err != nil {
+		logging.Logger.Warn("primary message bus unavailable, buffering job to disk", "error", err)
+		return b.store.Append(payload)
+	}
+
+	return nil
+}
+
+// drainLoop periodically flushes the disk buffer back into the primary
+// bus, backing off exponentially while the primary stays unreachable.
+func (b *DurableBus) drainLoop() {
+	backoff := drainInitialBackoff
+	ticker := time.NewTicker(backoff)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			if err := b.store.Drain(b.primary.Enqueue); err != nil {
+				logging.Logger.Warn("leveldb queue drain paused", "error", err)
+				backoff = nextBackoff(backoff)
+			} else {
+				backoff = drainInitialBackoff
+			}
+			ticker.Reset(backoff)
+		}
+	}
+}
+
+func nextBackoff(current time.Duration) time.Duration {
+	next := current * 2
+	if next > drainMaxBackoff {
+		return drainMaxBackoff
+	}
+	return next
+}
+
+// Close stops the drain loop and closes the underlying store and bus.
+func (b *DurableBus) Close() error {
+	close(b.stop)
+	if err := b.store.Close(); err != nil {
+		return err
+	}
+	return b.primary.Close()
+}
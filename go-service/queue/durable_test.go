@@ -0,0 +1,138 @@
+package queue
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeBus is a MessageBus whose availability can be toggled mid-test, to
+// simulate the primary backend (e.g. Redis/Sidekiq) going down and coming
+// back up without depending on a real broker.
+type fakeBus struct {
+	up int32 // 1 when Enqueue should succeed, 0 when it should fail
+
+	mu       sync.Mutex
+	received []ActiveJobPayload
+}
+
+func newFakeBus(up bool) *fakeBus {
+	b := &fakeBus{}
+	if up {
+		atomic.StoreInt32(&b.up, 1)
+	}
+	return b
+}
+
+func (b *fakeBus) setUp(up bool) {
+	if up {
+		atomic.StoreInt32(&b.up, 1)
+	} else {
+		atomic.StoreInt32(&b.up, 0)
+	}
+}
+
+func (b *fakeBus) Enqueue(payload ActiveJobPayload) error {
+	if atomic.LoadInt32(&b.up) == 0 {
+		return fmt.Errorf("fake bus is down")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.received = append(b.received, payload)
+	return nil
+}
+
+func (b *fakeBus) Close() error { return nil }
+
+func (b *fakeBus) jobIDs() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ids := make([]string, len(b.received))
+	for i, p := range b.received {
+		ids[i] = p.JobID
+	}
+	return ids
+}
+
+// TestDurableBusBuffersAndDrainsAfterOutage kills the primary backend mid-run,
+// enqueues jobs while it's down, then restarts it and asserts the drain loop
+// delivers every buffered job with nothing dropped.
+func TestDurableBusBuffersAndDrainsAfterOutage(t *testing.T) {
+	primary := newFakeBus(true)
+
+	b, err := NewDurableBus(primary, t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("NewDurableBus: %v", err)
+	}
+	t.Cleanup(func() { b.Close() })
+
+	// Primary is healthy: this job goes straight through.
+	if err := b.Enqueue(ActiveJobPayload{JobClass: "CreateChatJob", JobID: "job-1"}); err != nil {
+		t.Fatalf("Enqueue while primary is up: %v", err)
+	}
+
+	// Kill the primary backend mid-run.
+	primary.setUp(false)
+
+	if err := b.Enqueue(ActiveJobPayload{JobClass: "CreateChatJob", JobID: "job-2"}); err != nil {
+		t.Fatalf("Enqueue while primary is down should buffer, not error: %v", err)
+	}
+	if err := b.Enqueue(ActiveJobPayload{JobClass: "CreateMessageJob", JobID: "job-3"}); err != nil {
+		t.Fatalf("Enqueue while primary is down should buffer, not error: %v", err)
+	}
+
+	// Restart the primary backend and wait for the drain loop to flush it.
+	primary.setUp(true)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if len(primary.jobIDs()) == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("drain loop did not flush buffered jobs in time, got %v", primary.jobIDs())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	got := primary.jobIDs()
+	want := map[string]bool{"job-1": true, "job-2": true, "job-3": true}
+	if len(got) != len(want) {
+		t.Fatalf("expected exactly %d delivered jobs, got %v", len(want), got)
+	}
+	for _, id := range got {
+		if !want[id] {
+			t.Fatalf("unexpected job delivered: %s", id)
+		}
+	}
+}
+
+// TestDurableBusAlwaysBufferModeWritesToDiskFirst covers QUEUE_TYPE=leveldb,
+// where every job is buffered to disk up front regardless of whether the
+// primary is reachable.
+func TestDurableBusAlwaysBufferModeWritesToDiskFirst(t *testing.T) {
+	primary := newFakeBus(true)
+
+	b, err := NewDurableBus(primary, t.TempDir(), true)
+	if err != nil {
+		t.Fatalf("NewDurableBus: %v", err)
+	}
+	t.Cleanup(func() { b.Close() })
+
+	if err := b.Enqueue(ActiveJobPayload{JobClass: "CreateChatJob", JobID: "job-1"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if len(primary.jobIDs()) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("drain loop did not flush the buffered job in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
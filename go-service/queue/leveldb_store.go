@@ -0,0 +1,107 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// levelDBStore is a local disk-backed write-ahead queue of ActiveJob
+// payloads, used as a buffer while the primary MessageBus is unreachable.
+type levelDBStore struct {
+	mu   sync.Mutex
+	db   *leveldb.DB
+	next uint64
+}
+
+func openLevelDBStore(dir string) (*levelDBStore, error) {
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open leveldb queue at %s: %v", dir, err)
+	}
+
+	// Seed the sequence counter from whatever is already on disk, so a
+	// restart with undrained jobs from before the crash resumes after
+	// them instead of reusing their keys and overwriting them.
+	next, err := lastKeySeq(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to scan leveldb queue at %s: %v", dir, err)
+	}
+
+	return &levelDBStore{db: db, next: next}, nil
+}
+
+// lastKeySeq returns the sequence number of the highest existing key, or 0
+// if the store is empty. Keys are fixed-width zero-padded decimal strings,
+// so lexicographic order matches numeric order and the last key in the
+// iterator is the highest.
+func lastKeySeq(db *leveldb.DB) (uint64, error) {
+	iter := db.NewIterator(nil, nil)
+	defer iter.Release()
+
+	var last uint64
+	if iter.Last() {
+		var err error
+		last, err = strconv.ParseUint(string(iter.Key()), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unexpected leveldb queue key %q: %v", iter.Key(), err)
+		}
+	}
+
+	return last, iter.Error()
+}
+
+// Append persists a job payload, keyed so iteration returns FIFO order.
+func (s *levelDBStore) Append(payload ActiveJobPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job for leveldb queue: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.db.Put(s.nextKeyLocked(), body, nil)
+}
+
+func (s *levelDBStore) nextKeyLocked() []byte {
+	s.next++
+	return []byte(fmt.Sprintf("%020d", s.next))
+}
+
+// Drain invokes fn for every buffered job in FIFO order, deleting each
+// entry once fn returns nil. It stops at the first error so a still-
+// unreachable backend doesn't lose its place in line.
+func (s *levelDBStore) Drain(fn func(ActiveJobPayload) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	iter := s.db.NewIterator(nil, nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		var payload ActiveJobPayload
+		if err := json.Unmarshal(iter.Value(), &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal buffered job: %v", err)
+		}
+
+		if err := fn(payload); err != nil {
+			return err
+		}
+
+		key := append([]byte(nil), iter.Key()...)
+		if err := s.db.Delete(key, nil); err != nil {
+			return fmt.Errorf("failed to remove drained job: %v", err)
+		}
+	}
+
+	return iter.Error()
+}
+
+func (s *levelDBStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,52 @@
+package queue
+
+import "testing"
+
+func TestOpenLevelDBStoreResumesAfterUndrainedEntries(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := openLevelDBStore(dir)
+	if err != nil {
+		t.Fatalf("openLevelDBStore: %v", err)
+	}
+	if err := s.Append(ActiveJobPayload{JobClass: "CreateChatJob", JobID: "job-1"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := s.Append(ActiveJobPayload{JobClass: "CreateChatJob", JobID: "job-2"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	// Simulate a crash before these two jobs were drained: close and
+	// reopen the store without ever calling Drain.
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := openLevelDBStore(dir)
+	if err != nil {
+		t.Fatalf("openLevelDBStore (reopen): %v", err)
+	}
+	t.Cleanup(func() { reopened.Close() })
+
+	if err := reopened.Append(ActiveJobPayload{JobClass: "CreateChatJob", JobID: "job-3"}); err != nil {
+		t.Fatalf("Append after reopen: %v", err)
+	}
+
+	var drained []string
+	err = reopened.Drain(func(p ActiveJobPayload) error {
+		drained = append(drained, p.JobID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+
+	want := []string{"job-1", "job-2", "job-3"}
+	if len(drained) != len(want) {
+		t.Fatalf("expected %v, got %v (job-1/job-2 were clobbered by a key collision)", want, drained)
+	}
+	for i, id := range want {
+		if drained[i] != id {
+			t.Fatalf("expected %v, got %v (job-1/job-2 were clobbered by a key collision)", want, drained)
+		}
+	}
+}
@@ -3,10 +3,11 @@ package queue
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/luciq/chat-go-service/cache"
+	"github.com/luciq/chat-go-service/logging"
+	"github.com/luciq/chat-go-service/metrics"
 )
 
 // ActiveJobPayload represents the structure Rails ActiveJob expects
@@ -18,6 +19,54 @@ type ActiveJobPayload struct {
 	Locale    string        `json:"locale"`
 }
 
+// SidekiqBus is the MessageBus implementation backed by a Redis LPUSH,
+// matching the wire format Sidekiq's Redis queue adapter expects.
+type SidekiqBus struct{}
+
+// NewSidekiqBus returns a MessageBus that dispatches jobs directly onto the
+// Sidekiq Redis queues.
+func NewSidekiqBus() *SidekiqBus {
+	return &SidekiqBus{}
+}
+
+// Enqueue marshals the ActiveJob payload and pushes it to Sidekiq.
+func (b *SidekiqBus) Enqueue(payload ActiveJobPayload) error {
+	// Wrap in ActiveJob::QueueAdapters::SidekiqAdapter format
+	wrapper := map[string]interface{}{
+		"class": "ActiveJob::QueueAdapters::SidekiqAdapter::JobWrapper",
+		"wrapped": payload.JobClass,
+		"queue": payload.QueueName,
+		"args": []interface{}{payload},
+		"retry": true,
+		"jid": payload.JobID,
+		"created_at": float64(time.Now().Unix()),
+	}
+
+	jobJSON, err := json.Marshal(wrapper)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %v", err)
+	}
+
+	queueKey := fmt.Sprintf("queue:%s", payload.QueueName)
+
+	// Push to Redis list (LPUSH for Sidekiq compatibility)
+	err = metrics.ObserveQueueEnqueue("sidekiq", func() error {
+		return cache.RedisClient.LPush(cache.Ctx, queueKey, string(jobJSON)).Err()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enqueue job: %v", err)
+	}
+
+	logging.Logger.Info("enqueued job", "job_class", payload.JobClass, "job_id", payload.JobID)
+	return nil
+}
+
+// Close is a no-op: the Sidekiq bus reuses the shared Redis connection,
+// which is closed separately via cache.CloseRedis.
+func (b *SidekiqBus) Close() error {
+	return nil
+}
+
 // EnqueueCreateChatJob queues a CreateChatJob via ActiveJob
 func EnqueueCreateChatJob(chatApplicationID int64, chatNumber int64) error {
 	payload := ActiveJobPayload{
@@ -49,32 +98,11 @@ func generateJobID() string {
 	return fmt.Sprintf("%d", time.Now().UnixNano())
 }
 
-// enqueueActiveJob marshals the ActiveJob payload and pushes it to Sidekiq
+// enqueueActiveJob dispatches the ActiveJob payload through the configured
+// MessageBus, falling back to a fresh SidekiqBus if Init was never called.
 func enqueueActiveJob(payload ActiveJobPayload) error {
-	// Wrap in ActiveJob::QueueAdapters::SidekiqAdapter format
-	wrapper := map[string]interface{}{
-		"class": "ActiveJob::QueueAdapters::SidekiqAdapter::JobWrapper",
-		"wrapped": payload.JobClass,
-		"queue": payload.QueueName,
-		"args": []interface{}{payload},
-		"retry": true,
-		"jid": payload.JobID,
-		"created_at": float64(time.Now().Unix()),
+	if bus == nil {
+		bus = NewSidekiqBus()
 	}
-
-	jobJSON, err := json.Marshal(wrapper)
-	if err != nil {
-		return fmt.Errorf("failed to marshal job: %v", err)
-	}
-
-	queueKey := fmt.Sprintf("queue:%s", payload.QueueName)
-
-	// Push to Redis list (LPUSH for Sidekiq compatibility)
-	err = cache.RedisClient.LPush(cache.Ctx, queueKey, string(jobJSON)).Err()
-	if err != nil {
-		return fmt.Errorf("failed to enqueue job: %v", err)
-	}
-
-	log.Printf("Enqueued %s: %s", payload.JobClass, string(jobJSON))
-	return nil
+	return bus.Enqueue(payload)
 }
@@ -0,0 +1,100 @@
+package ws
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/luciq/chat-go-service/logging"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingInterval   = (pongWait * 9) / 10
+	maxMessageSize = 4096
+)
+
+// Connection wraps a single client's WebSocket, joined to one chat Room.
+// Writes are serialized through a buffered send channel so a slow client
+// can't block the broadcaster.
+type Connection struct {
+	ws     *websocket.Conn
+	room   *Room
+	userID string
+	send   chan []byte
+}
+
+// NewConnection wraps an upgraded WebSocket for a given user id. The
+// room is assigned by the Hub before Run is called.
+func NewConnection(wsConn *websocket.Conn, userID string) *Connection {
+	return &Connection{
+		ws:     wsConn,
+		userID: userID,
+		send:   make(chan []byte, sendBufferSize),
+	}
+}
+
+// Run blocks, pumping writes and heartbeats until the socket closes, then
+// leaves the connection's room. The Hub has already joined the connection
+// to its room before calling Run. Call from its own goroutine.
+func (c *Connection) Run() {
+	defer c.room.Leave(c)
+
+	go c.readPump()
+	c.writePump()
+}
+
+// readPump only exists to process control frames (pong, close) and detect
+// a client disconnect; the client never sends chat content over this
+// socket.
+func (c *Connection) readPump() {
+	defer c.ws.Close()
+
+	c.ws.SetReadLimit(maxMessageSize)
+	c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	c.ws.SetPongHandler(func(string) error {
+		c.ws.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	for {
+		if _, _, err := c.ws.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (c *Connection) writePump() {
+	ticker := time.NewTicker(pingInterval)
+	defer func() {
+		ticker.Stop()
+		c.ws.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.ws.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.ws.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Close closes the underlying socket; the read/write pumps observe the
+// resulting error and unwind.
+func (c *Connection) Close() {
+	if err := c.ws.Close(); err != nil {
+		logging.Logger.Warn("error closing websocket connection", "error", err)
+	}
+}
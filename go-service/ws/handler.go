@@ -0,0 +1,68 @@
+package ws
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/luciq/chat-go-service/db"
+	"github.com/luciq/chat-go-service/logging"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Development: allow all origins.
+	// Production: restrict via the embedding app's domain.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// StreamHandler serves the chat WebSocket endpoint.
+type StreamHandler struct {
+	hub     *Hub
+	userIDs *UserIDCalculator
+}
+
+// NewStreamHandler builds a StreamHandler backed by the given Hub.
+func NewStreamHandler(hub *Hub) *StreamHandler {
+	return &StreamHandler{hub: hub, userIDs: NewUserIDCalculator()}
+}
+
+// Stream handles GET /api/v1/chat_applications/:token/chats/:number/stream,
+// upgrading to a WebSocket and streaming new messages for that chat.
+func (h *StreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	token := vars["token"]
+	chatNumberStr := vars["number"]
+	logging.SetToken(r.Context(), token)
+
+	chatNumber, err := strconv.ParseInt(chatNumberStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid chat number", http.StatusBadRequest)
+		return
+	}
+
+	chatAppID, err := db.GetChatApplicationID(token)
+	if err != nil {
+		http.Error(w, "ChatApplication not found", http.StatusNotFound)
+		return
+	}
+
+	chatID, err := db.GetChatID(chatAppID, chatNumber)
+	if err != nil {
+		http.Error(w, "Chat not found", http.StatusNotFound)
+		return
+	}
+	logging.SetChatID(r.Context(), chatID)
+
+	wsConn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("failed to upgrade websocket connection", "error", err)
+		return
+	}
+
+	userID := h.userIDs.Calculate(r, token)
+	conn := NewConnection(wsConn, userID)
+	h.hub.Join(chatID, conn)
+}
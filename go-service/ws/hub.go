@@ -0,0 +1,71 @@
+package ws
+
+import (
+	"sync"
+
+	"github.com/luciq/chat-go-service/cache"
+)
+
+// Hub owns one Room per chat, keeping a Redis subscription alive for a
+// chat for as long as it has at least one connected client.
+type Hub struct {
+	cache *cache.Client
+
+	mu    sync.Mutex
+	rooms map[int64]*Room
+}
+
+// NewHub builds a Hub backed by the given cache.Client's pub/sub.
+func NewHub(cacheClient *cache.Client) *Hub {
+	return &Hub{
+		cache: cacheClient,
+		rooms: make(map[int64]*Room),
+	}
+}
+
+// Join attaches conn to the room for chatID, starting that chat's Redis
+// subscription if conn is the first connection, and blocks until conn
+// disconnects. conn is registered into the room while h.mu is still held,
+// so a concurrent Join/teardown for the same chat can't observe the room
+// as empty in the window between picking it and conn actually joining it.
+func (h *Hub) Join(chatID int64, conn *Connection) {
+	h.mu.Lock()
+	room, exists := h.rooms[chatID]
+	if !exists {
+		room = NewRoom(chatID)
+		h.rooms[chatID] = room
+		go h.subscribe(chatID, room)
+	}
+	conn.room = room
+	room.Join(conn)
+	h.mu.Unlock()
+
+	conn.Run()
+
+	h.mu.Lock()
+	if room.Empty() {
+		delete(h.rooms, chatID)
+		room.Close()
+	}
+	h.mu.Unlock()
+}
+
+// subscribe fans Redis pub/sub messages for one chat out to its local
+// Room until the room empties out.
+func (h *Hub) subscribe(chatID int64, room *Room) {
+	sub := h.cache.SubscribeChatMessages(chatID)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			room.Broadcast([]byte(msg.Payload))
+		case <-room.Done():
+			return
+		}
+	}
+}
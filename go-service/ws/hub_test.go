@@ -0,0 +1,132 @@
+package ws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/websocket"
+	"github.com/luciq/chat-go-service/cache"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestCacheClient(t *testing.T) *cache.Client {
+	t.Helper()
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	t.Cleanup(mr.Close)
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	return cache.NewClient(rdb)
+}
+
+// TestHubJoinHandlesConcurrentJoinAndLeave dials and immediately tears down
+// many connections to the same chat concurrently. It's a regression test
+// for the Hub.Join race fixed previously (joining the room outside h.mu
+// let a concurrent teardown see the room as empty while a join was still
+// in flight); run with -race to catch it coming back.
+func TestHubJoinHandlesConcurrentJoinAndLeave(t *testing.T) {
+	hub := NewHub(newTestCacheClient(t))
+
+	var upgrader websocket.Upgrader
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wsConn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		hub.Join(1, NewConnection(wsConn, "user"))
+	}))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	const connections = 20
+	var wg sync.WaitGroup
+	for i := 0; i < connections; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+			if err != nil {
+				t.Errorf("dial: %v", err)
+				return
+			}
+			time.Sleep(time.Millisecond)
+			conn.Close()
+		}()
+	}
+	wg.Wait()
+
+	// Each connection's writePump only notices its socket is gone once it
+	// next tries to write (a broadcast or a ping, whichever comes first),
+	// so nudge it along with a broadcast instead of waiting out the real
+	// ping interval.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		hub.cache.PublishChatMessage(1, []byte("cleanup"))
+
+		hub.mu.Lock()
+		_, exists := hub.rooms[1]
+		hub.mu.Unlock()
+		if !exists {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the hub to clean up the room once every connection had left")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestRoomBroadcastDropsSlowConsumer covers Room.Broadcast's slow-consumer
+// path: a connection whose send buffer is already full gets its socket
+// closed instead of blocking the rest of the room's broadcasts.
+func TestRoomBroadcastDropsSlowConsumer(t *testing.T) {
+	serverConns := make(chan *websocket.Conn, 1)
+
+	var upgrader websocket.Upgrader
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wsConn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		serverConns <- wsConn
+	}))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	clientConn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer clientConn.Close()
+
+	serverConn := <-serverConns
+	conn := NewConnection(serverConn, "slow-user")
+
+	room := NewRoom(1)
+	room.Join(conn)
+
+	// Fill the send buffer. Nothing is draining it since writePump was
+	// never started, so these should all succeed.
+	for i := 0; i < sendBufferSize; i++ {
+		room.Broadcast([]byte("msg"))
+	}
+
+	// This one overflows the buffer and should drop the connection.
+	room.Broadcast([]byte("overflow"))
+
+	clientConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := clientConn.ReadMessage(); err == nil {
+		t.Fatal("expected the slow consumer's connection to be closed after its send buffer overflowed")
+	}
+}
@@ -0,0 +1,80 @@
+package ws
+
+import (
+	"sync"
+
+	"github.com/luciq/chat-go-service/logging"
+)
+
+// sendBufferSize bounds how many pending broadcasts a connection can
+// queue before it's treated as a slow consumer and dropped.
+const sendBufferSize = 16
+
+// Room fans broadcasts out to every connection joined to one chat.
+type Room struct {
+	chatID int64
+
+	mu    sync.RWMutex
+	conns map[*Connection]struct{}
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewRoom creates an empty Room for a chat.
+func NewRoom(chatID int64) *Room {
+	return &Room{
+		chatID: chatID,
+		conns:  make(map[*Connection]struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Join adds a connection to the room.
+func (r *Room) Join(conn *Connection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.conns[conn] = struct{}{}
+}
+
+// Leave removes a connection from the room.
+func (r *Room) Leave(conn *Connection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.conns, conn)
+}
+
+// Empty reports whether the room has no connections left.
+func (r *Room) Empty() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.conns) == 0
+}
+
+// Broadcast fans a message out to every connection in the room. A
+// connection whose send buffer is already full is treated as a slow
+// consumer and dropped rather than blocking the rest of the room.
+func (r *Room) Broadcast(message []byte) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for conn := range r.conns {
+		select {
+		case conn.send <- message:
+		default:
+			logging.Logger.Warn("dropping slow consumer", "chat_id", r.chatID)
+			go conn.Close()
+		}
+	}
+}
+
+// Done returns a channel closed once the room's Redis subscription should
+// stop, i.e. after its last connection leaves.
+func (r *Room) Done() <-chan struct{} {
+	return r.done
+}
+
+// Close signals Done, stopping the room's Redis subscription.
+func (r *Room) Close() {
+	r.closeOnce.Do(func() { close(r.done) })
+}
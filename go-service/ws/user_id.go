@@ -0,0 +1,48 @@
+package ws
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"os"
+)
+
+const defaultUserIDSalt = "chat-go-service-default-salt"
+
+// UserIDCalculator derives a stable anonymous user id for a connection
+// from its IP and chat application token, without any auth system. The
+// same client hitting the same application always gets the same id, which
+// is enough for a client to recognize its own echoed messages.
+type UserIDCalculator struct {
+	salt string
+}
+
+// NewUserIDCalculator builds a UserIDCalculator using WS_USER_ID_SALT, or
+// a package default if unset.
+func NewUserIDCalculator() *UserIDCalculator {
+	salt := os.Getenv("WS_USER_ID_SALT")
+	if salt == "" {
+		salt = defaultUserIDSalt
+	}
+	return &UserIDCalculator{salt: salt}
+}
+
+// Calculate derives the user id for a request scoped to a chat
+// application token.
+func (c *UserIDCalculator) Calculate(r *http.Request, token string) string {
+	mac := hmac.New(sha256.New, []byte(c.salt))
+	mac.Write([]byte(clientIP(r)))
+	mac.Write([]byte{0})
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}